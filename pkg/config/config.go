@@ -1,15 +1,20 @@
 package config
 
 import (
-	"bytes"
-	"encoding/binary"
 	"fmt"
 	"net"
+	"net/netip"
 
 	"github.com/exaring/matroschka-prober/pkg/prober"
 	"github.com/pkg/errors"
 )
 
+// maxGeneratedAddrs caps the number of addresses GenerateAddrs will
+// materialize for a single CIDR. IPv4 ranges used in practice are always
+// well within this limit; it mainly guards against accidentally configuring
+// an enormous IPv6 range.
+const maxGeneratedAddrs = 1 << 20
+
 var (
 	dfltBasePort = uint16(32768)
 	dfltClass    = Class{
@@ -21,7 +26,14 @@ var (
 	dfltMeasurementLengthMS = uint64(1000)
 	dfltPayloadSizeBytes    = uint64(0)
 	dfltPPS                 = uint64(25)
+	dfltTimestamping        = "userspace"
+	dfltProbeType           = "udp"
+	dfltHistogramMinMS      = float64(0.01)
+	dfltHistogramMaxMS      = float64(60000)
+	dfltHistogramPrecision  = 3
+	dfltLogFormatter        = "text"
 	dfltSrcRange            = "169.254.0.0/16"
+	dfltSrcRange6           = "fe80::/64"
 	dfltMetricsPath         = "/metrics"
 )
 
@@ -54,6 +66,43 @@ type Config struct {
 	// description: |
 	//   List of routers used as explicit hops in the path.
 	Routers []Router `yaml:"routers"`
+	// description: |
+	//   Logging configuration.
+	Logging *Logging `yaml:"logging"`
+}
+
+// Logging configures the log formatter and one or more destinations
+// ("sinks") log messages are written to.
+type Logging struct {
+	// description: |
+	//   Log line format, `text` or `json`. Defaults to `text`.
+	Formatter *string `yaml:"formatter"`
+	// description: |
+	//   Destinations log messages are written to. Defaults to a single
+	//   `stderr` sink.
+	Sinks []LogSink `yaml:"sinks"`
+}
+
+// LogSink configures a single logging destination.
+type LogSink struct {
+	// description: |
+	//   Sink type: `stderr`, `syslog`, `journald` or `file`.
+	Type string `yaml:"type"`
+	// description: |
+	//   Path of the log file. Only used if Type is `file`.
+	Path *string `yaml:"path"`
+	// description: |
+	//   Maximum size in megabytes before the log file is rotated. Only
+	//   used if Type is `file`.
+	MaxSizeMB *int `yaml:"max_size_mb"`
+	// description: |
+	//   Number of rotated log files to keep. Only used if Type is `file`.
+	MaxBackups *int `yaml:"max_backups"`
+	// description: |
+	//   Network and address of the syslog daemon, e.g. `udp@127.0.0.1:514`.
+	//   Only used if Type is `syslog`. Leave empty to use the local syslog
+	//   daemon.
+	SyslogAddr *string `yaml:"syslog_addr"`
 }
 
 // Defaults represents the default section of the config
@@ -79,6 +128,26 @@ type Defaults struct {
 	// description: |
 	//  Source Interface
 	SrcInterface *string `yaml:"src_interface"`
+	// description: |
+	//   Latency histogram settings used to derive the `_bucket`/`_sum`/
+	//   `_count` series and p50/p95/p99/p999 gauges.
+	Histogram *Histogram `yaml:"histogram"`
+}
+
+// Histogram configures the log-linear latency histogram kept for a path.
+type Histogram struct {
+	// description: |
+	//   Lowest latency (in milliseconds) the histogram can distinguish from
+	//   zero.
+	MinMS *float64 `yaml:"min_ms"`
+	// description: |
+	//   Highest latency (in milliseconds) trackable by the histogram.
+	//   Samples above this value are clamped into the top bucket.
+	MaxMS *float64 `yaml:"max_ms"`
+	// description: |
+	//   Number of significant decimal digits of precision kept for each
+	//   recorded value, analogous to HdrHistogram's `sigfigs` (1-5).
+	Precision *int `yaml:"precision"`
 }
 
 // Class reperesnets a traffic class in the config file
@@ -97,6 +166,16 @@ type Path struct {
 	//   Name for the path.
 	Name string `yaml:"name"`
 	// description: |
+	//   Short name used in log fields (`path=<alias>`) instead of Name, to
+	//   keep log lines readable when Name is long or contains spaces.
+	//   Defaults to Name.
+	Alias *string `yaml:"alias"`
+	// description: |
+	//   Probe type to use for this path, e.g. `udp`. Must be a type
+	//   registered in pkg/prober, either built in or added via a blank
+	//   import of a pkg/prober/probes/* package. Defaults to `udp`.
+	Type *string `yaml:"type"`
+	// description: |
 	//   List of hops to probe.
 	Hops []string `yaml:"hops"`
 	// description: |
@@ -111,6 +190,16 @@ type Path struct {
 	// description: |
 	//   Timeout expressed in milliseconds.
 	TimeoutMS *uint64 `yaml:"timeout"`
+	// description: |
+	//   Source of the receive timestamp used to calculate RTTs. One of
+	//   `userspace` (default, timestamp taken after the read syscall
+	//   returns), `kernel` (SO_TIMESTAMPNS, Linux only) or `hardware`
+	//   (SO_TIMESTAMPING, Linux only, requires NIC support).
+	Timestamping *string `yaml:"timestamping"`
+	// description: |
+	//   Per-path override of the latency histogram settings. Unset fields
+	//   fall back to the defaults section.
+	Histogram *Histogram `yaml:"histogram"`
 }
 
 // Router represents a router used a an explicit hop in a path
@@ -124,56 +213,149 @@ type Router struct {
 	// description: |
 	//   Range of source ip addresses.
 	SrcRange string `yaml:"src_range"`
+	// docgen:nodoc
+	// this member is not configured on the yaml file, it is auto-detected
+	// from DstRange once the config has been validated.
+	Family string `yaml:"-"`
 }
 
 // Validate validates a configuration
 func (c *Config) Validate() error {
-	err := c.validatePaths()
+	// validateRouters must run first: it populates Router.Family, which
+	// validatePaths relies on to reject paths that mix address families.
+	err := c.validateRouters()
+	if err != nil {
+		return fmt.Errorf("Router validation failed: %v", err)
+	}
+
+	err = c.validatePaths()
 	if err != nil {
 		return fmt.Errorf("Path validation failed: %v", err)
 	}
 
-	err = c.validateRouters()
+	err = c.validateLogging()
 	if err != nil {
-		return fmt.Errorf("Router validation failed: %v", err)
+		return fmt.Errorf("Logging validation failed: %v", err)
+	}
+
+	return nil
+}
+
+var validLogFormatters = map[string]bool{
+	"":     true,
+	"text": true,
+	"json": true,
+}
+
+var validLogSinkTypes = map[string]bool{
+	"stderr":   true,
+	"syslog":   true,
+	"journald": true,
+	"file":     true,
+}
+
+func (c *Config) validateLogging() error {
+	if c.Logging == nil {
+		return nil
+	}
+
+	if c.Logging.Formatter != nil && !validLogFormatters[*c.Logging.Formatter] {
+		return fmt.Errorf("Unknown log formatter %q", *c.Logging.Formatter)
+	}
+
+	for i := range c.Logging.Sinks {
+		s := c.Logging.Sinks[i]
+		if !validLogSinkTypes[s.Type] {
+			return fmt.Errorf("Unknown log sink type %q", s.Type)
+		}
+
+		if s.Type == "file" && (s.Path == nil || *s.Path == "") {
+			return fmt.Errorf("Log sink of type \"file\" requires a path")
+		}
 	}
 
 	return nil
 }
 
+// validTimestampingModes enumerates the accepted values of Path.Timestamping.
+var validTimestampingModes = map[string]bool{
+	"":          true,
+	"userspace": true,
+	"kernel":    true,
+	"hardware":  true,
+}
+
 func (c *Config) validatePaths() error {
 	for i := range c.Paths {
+		pathFamily := ""
 		for j := range c.Paths[i].Hops {
-			if !c.routerExists(c.Paths[i].Hops[j]) {
+			r, ok := c.router(c.Paths[i].Hops[j])
+			if !ok {
 				return fmt.Errorf("Router %q of path %q does not exist", c.Paths[i].Hops[j], c.Paths[i].Name)
 			}
+
+			if pathFamily == "" {
+				pathFamily = r.Family
+			} else if r.Family != pathFamily {
+				return fmt.Errorf("Path %q mixes address families: hop %q is %s, rest of the path is %s", c.Paths[i].Name, r.Name, r.Family, pathFamily)
+			}
+		}
+
+		if c.Paths[i].Timestamping != nil && !validTimestampingModes[*c.Paths[i].Timestamping] {
+			return fmt.Errorf("Path %q has invalid timestamping mode %q", c.Paths[i].Name, *c.Paths[i].Timestamping)
+		}
+
+		if c.Paths[i].Type != nil {
+			if _, ok := prober.Lookup(*c.Paths[i].Type); !ok {
+				return fmt.Errorf("Path %q has unknown probe type %q (registered: %v)", c.Paths[i].Name, *c.Paths[i].Type, prober.Registered())
+			}
 		}
 	}
 
 	return nil
 }
 
-func (c *Config) routerExists(needle string) bool {
+func (c *Config) router(needle string) (Router, bool) {
 	for i := range c.Routers {
 		if c.Routers[i].Name == needle {
-			return true
+			return c.Routers[i], true
 		}
 	}
 
-	return false
+	return Router{}, false
 }
 
 func (c *Config) validateRouters() error {
 	for i := range c.Routers {
-		_, _, err := net.ParseCIDR(c.Routers[i].DstRange)
+		dst, err := netip.ParsePrefix(c.Routers[i].DstRange)
 		if err != nil {
 			return fmt.Errorf("Unable to parse dst IP range for router %q: %v", c.Routers[i].Name, err)
 		}
+
+		src, err := netip.ParsePrefix(c.Routers[i].SrcRange)
+		if err != nil {
+			return fmt.Errorf("Unable to parse src IP range for router %q: %v", c.Routers[i].Name, err)
+		}
+
+		if dst.Addr().Is4() != src.Addr().Is4() {
+			return fmt.Errorf("dst and src IP range of router %q are of different address families", c.Routers[i].Name)
+		}
+
+		c.Routers[i].Family = addrFamily(dst.Addr())
 	}
 
 	return nil
 }
 
+// addrFamily returns "ipv4" or "ipv6" for addr.
+func addrFamily(addr netip.Addr) string {
+	if addr.Is4() {
+		return "ipv4"
+	}
+
+	return "ipv6"
+}
+
 // ApplyDefaults applies default settings if they are missing from loaded config.
 func (c *Config) ApplyDefaults() {
 	if c.Defaults == nil {
@@ -210,11 +392,35 @@ func (c *Config) ApplyDefaults() {
 			dfltClass,
 		}
 	}
+
+	if c.Logging == nil {
+		c.Logging = &Logging{}
+	}
+	c.Logging.applyDefaults()
+}
+
+func (l *Logging) applyDefaults() {
+	if l.Formatter == nil {
+		l.Formatter = &dfltLogFormatter
+	}
+
+	if len(l.Sinks) == 0 {
+		l.Sinks = []LogSink{
+			{Type: "stderr"},
+		}
+	}
 }
 
 func (r *Router) applyDefaults(d *Defaults) {
-	if r.SrcRange == "" {
-		r.SrcRange = *d.SrcRange
+	if r.SrcRange != "" {
+		return
+	}
+
+	r.SrcRange = *d.SrcRange
+
+	dst, err := netip.ParsePrefix(r.DstRange)
+	if err == nil && !dst.Addr().Is4() {
+		r.SrcRange = dfltSrcRange6
 	}
 }
 
@@ -234,6 +440,37 @@ func (p *Path) applyDefaults(d *Defaults) {
 	if p.TimeoutMS == nil {
 		p.TimeoutMS = d.TimeoutMS
 	}
+
+	if p.Timestamping == nil {
+		p.Timestamping = &dfltTimestamping
+	}
+
+	if p.Type == nil {
+		p.Type = &dfltProbeType
+	}
+
+	if p.Histogram == nil {
+		p.Histogram = &Histogram{}
+	}
+	p.Histogram.applyDefaultsFrom(d.Histogram)
+
+	if p.Alias == nil {
+		p.Alias = &p.Name
+	}
+}
+
+func (h *Histogram) applyDefaultsFrom(d *Histogram) {
+	if h.MinMS == nil {
+		h.MinMS = d.MinMS
+	}
+
+	if h.MaxMS == nil {
+		h.MaxMS = d.MaxMS
+	}
+
+	if h.Precision == nil {
+		h.Precision = d.Precision
+	}
 }
 
 func (d *Defaults) applyDefaults() {
@@ -256,6 +493,25 @@ func (d *Defaults) applyDefaults() {
 	if d.TimeoutMS == nil {
 		d.TimeoutMS = &dfltTimeoutMS
 	}
+
+	if d.Histogram == nil {
+		d.Histogram = &Histogram{}
+	}
+	d.Histogram.applyDefaults()
+}
+
+func (h *Histogram) applyDefaults() {
+	if h.MinMS == nil {
+		h.MinMS = &dfltHistogramMinMS
+	}
+
+	if h.MaxMS == nil {
+		h.MaxMS = &dfltHistogramMaxMS
+	}
+
+	if h.Precision == nil {
+		h.Precision = &dfltHistogramPrecision
+	}
 }
 
 // GetConfiguredSrcAddr gets an IPv4 address of the configured src interface
@@ -317,55 +573,50 @@ func (c *Config) PathToProberHops(pathCfg Path) []prober.Hop {
 	return res
 }
 
-// GenerateAddrs returns a list of all IPs in addrRange
+// GenerateAddrs returns a list of the IPs in addrRange, starting at the
+// network address and holding at most maxGeneratedAddrs of them. Both IPv4
+// and IPv6 CIDRs are supported; the address family is auto-detected from
+// addrRange.
 func GenerateAddrs(addrRange string) []net.IP {
-	_, n, err := net.ParseCIDR(addrRange)
+	prefix, err := netip.ParsePrefix(addrRange)
 	if err != nil {
 		panic(err)
 	}
 
-	baseAddr := getCIDRBase(*n)
-	c := maskAddrCount(*n)
-	ret := make([]net.IP, c)
+	prefix = prefix.Masked()
+	c := maskAddrCount(prefix)
+	ret := make([]net.IP, 0, c)
 
-	for i := uint32(0); i < c; i++ {
-		ret[i] = net.IP(uint32Byte(baseAddr + i%c))
+	for addr := prefix.Addr(); prefix.Contains(addr) && uint64(len(ret)) < c; addr = addr.Next() {
+		ret = append(ret, net.IP(addr.AsSlice()))
 	}
 
 	return ret
 }
 
-func getCIDRBase(n net.IPNet) uint32 {
-	return uint32b(n.IP)
-}
+func getNthAddr(prefix netip.Prefix, i uint64) net.IP {
+	prefix = prefix.Masked()
+	c := maskAddrCount(prefix)
+	addr := prefix.Addr()
 
-func uint32b(data []byte) (ret uint32) {
-	buf := bytes.NewBuffer(data)
-	binary.Read(buf, binary.BigEndian, &ret)
-	return
-}
+	for n := i % c; n > 0; n-- {
+		addr = addr.Next()
+	}
 
-func getNthAddr(n net.IPNet, i uint32) net.IP {
-	baseAddr := getCIDRBase(n)
-	c := maskAddrCount(n)
-	return net.IP(uint32Byte(baseAddr + i%c))
+	return net.IP(addr.AsSlice())
 }
 
-func maskAddrCount(n net.IPNet) uint32 {
-	ones, bits := n.Mask.Size()
-	if ones == bits {
+// maskAddrCount returns the number of addresses covered by prefix, capped at
+// maxGeneratedAddrs so that a broad IPv6 range can't exhaust memory.
+func maskAddrCount(prefix netip.Prefix) uint64 {
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits <= 0 {
 		return 1
 	}
 
-	x := uint32(1)
-	for i := ones; i < bits; i++ {
-		x = x * 2
+	if hostBits > 20 {
+		return maxGeneratedAddrs
 	}
-	return x
-}
 
-func uint32Byte(data uint32) (ret []byte) {
-	buf := new(bytes.Buffer)
-	binary.Write(buf, binary.BigEndian, data)
-	return buf.Bytes()
+	return uint64(1) << uint(hostBits)
 }
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"testing"
+
+	// Register the "udp" probe type so Validate() accepts paths using it.
+	_ "github.com/exaring/matroschka-prober/pkg/prober/probes/udp"
+)
+
+func TestGenerateAddrsIPv4(t *testing.T) {
+	addrs := GenerateAddrs("192.0.2.0/30")
+	if len(addrs) != 4 {
+		t.Fatalf("expected 4 addresses, got %d", len(addrs))
+	}
+
+	if addrs[0].String() != "192.0.2.0" {
+		t.Errorf("expected first address 192.0.2.0, got %s", addrs[0])
+	}
+
+	if addrs[3].String() != "192.0.2.3" {
+		t.Errorf("expected last address 192.0.2.3, got %s", addrs[3])
+	}
+}
+
+func TestGenerateAddrsIPv6(t *testing.T) {
+	addrs := GenerateAddrs("2001:db8::/126")
+	if len(addrs) != 4 {
+		t.Fatalf("expected 4 addresses, got %d", len(addrs))
+	}
+
+	if addrs[0].String() != "2001:db8::" {
+		t.Errorf("expected first address 2001:db8::, got %s", addrs[0])
+	}
+
+	if addrs[3].String() != "2001:db8::3" {
+		t.Errorf("expected last address 2001:db8::3, got %s", addrs[3])
+	}
+}
+
+func mixedFamilyConfig() *Config {
+	return &Config{
+		Routers: []Router{
+			{Name: "r4", DstRange: "192.0.2.0/30", SrcRange: "198.51.100.0/30"},
+			{Name: "r6", DstRange: "2001:db8::/126", SrcRange: "2001:db8:1::/126"},
+		},
+		Paths: []Path{
+			{Name: "mixed", Hops: []string{"r4", "r6"}},
+		},
+	}
+}
+
+func TestValidateRejectsMixedFamilyPath(t *testing.T) {
+	c := mixedFamilyConfig()
+	c.ApplyDefaults()
+
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("expected Validate to reject a path mixing address families, got nil error")
+	}
+}
+
+func TestValidateAcceptsSingleFamilyPaths(t *testing.T) {
+	c := &Config{
+		Routers: []Router{
+			{Name: "r4a", DstRange: "192.0.2.0/30", SrcRange: "198.51.100.0/30"},
+			{Name: "r4b", DstRange: "192.0.2.4/30", SrcRange: "198.51.100.4/30"},
+			{Name: "r6", DstRange: "2001:db8::/126", SrcRange: "2001:db8:1::/126"},
+		},
+		Paths: []Path{
+			{Name: "v4", Hops: []string{"r4a", "r4b"}},
+			{Name: "v6", Hops: []string{"r6"}},
+		},
+	}
+	c.ApplyDefaults()
+
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, r := range c.Routers {
+		if r.Family == "" {
+			t.Errorf("router %q: Family was not populated by Validate", r.Name)
+		}
+	}
+}
+
+func TestValidateRejectsMismatchedRouterFamily(t *testing.T) {
+	c := &Config{
+		Routers: []Router{
+			{Name: "bad", DstRange: "192.0.2.0/30", SrcRange: "2001:db8::/126"},
+		},
+	}
+	c.ApplyDefaults()
+
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected Validate to reject a router with mismatched src/dst families, got nil error")
+	}
+}
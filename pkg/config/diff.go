@@ -0,0 +1,125 @@
+package config
+
+// PathDiff describes how the Paths of two configurations differ, keyed by
+// path name. It is used by the daemon to reconcile running probers after a
+// config reload.
+type PathDiff struct {
+	// Added contains paths present in the new config but not the old one.
+	// A prober must be started for each of them.
+	Added []Path
+	// Removed contains paths present in the old config but not the new
+	// one. Their prober must be stopped.
+	Removed []Path
+	// Changed contains paths present in both configs whose settings
+	// differ. Their prober must be restarted.
+	Changed []Path
+	// Unchanged contains paths present in both configs with identical
+	// settings. Their prober and metrics state must be left running so
+	// that scrapes don't see counter resets.
+	Unchanged []Path
+}
+
+// DiffPaths compares the Paths of c (the new config) against old. Both
+// configs must already have had ApplyDefaults called on them, so that
+// defaulted fields compare equal when the user left them unset in either
+// version of the file.
+func (c *Config) DiffPaths(old *Config) PathDiff {
+	oldByName := make(map[string]Path, len(old.Paths))
+	for _, p := range old.Paths {
+		oldByName[p.Name] = p
+	}
+
+	var diff PathDiff
+	seen := make(map[string]bool, len(c.Paths))
+
+	for _, p := range c.Paths {
+		seen[p.Name] = true
+
+		o, ok := oldByName[p.Name]
+		if !ok {
+			diff.Added = append(diff.Added, p)
+			continue
+		}
+
+		if p.equal(o) {
+			diff.Unchanged = append(diff.Unchanged, p)
+		} else {
+			diff.Changed = append(diff.Changed, p)
+		}
+	}
+
+	for _, p := range old.Paths {
+		if !seen[p.Name] {
+			diff.Removed = append(diff.Removed, p)
+		}
+	}
+
+	return diff
+}
+
+// equal reports whether p and o have identical settings, i.e. whether a
+// restart is necessary after a reload. Only the Path's own fields are
+// compared: hops are compared by router name, so a change to the definition
+// of a router a path uses does not make the path unequal.
+func (p Path) equal(o Path) bool {
+	if p.Name != o.Name || len(p.Hops) != len(o.Hops) {
+		return false
+	}
+
+	for i := range p.Hops {
+		if p.Hops[i] != o.Hops[i] {
+			return false
+		}
+	}
+
+	return equalUint64Ptr(p.MeasurementLengthMS, o.MeasurementLengthMS) &&
+		equalUint64Ptr(p.PayloadSizeBytes, o.PayloadSizeBytes) &&
+		equalUint64Ptr(p.PPS, o.PPS) &&
+		equalUint64Ptr(p.TimeoutMS, o.TimeoutMS) &&
+		equalStringPtr(p.Timestamping, o.Timestamping) &&
+		equalStringPtr(p.Type, o.Type) &&
+		equalStringPtr(p.Alias, o.Alias) &&
+		p.Histogram.equal(o.Histogram)
+}
+
+func (h *Histogram) equal(o *Histogram) bool {
+	if h == nil || o == nil {
+		return h == o
+	}
+
+	return equalFloat64Ptr(h.MinMS, o.MinMS) &&
+		equalFloat64Ptr(h.MaxMS, o.MaxMS) &&
+		equalIntPtr(h.Precision, o.Precision)
+}
+
+func equalFloat64Ptr(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+func equalIntPtr(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+func equalUint64Ptr(a, b *uint64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+func equalStringPtr(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
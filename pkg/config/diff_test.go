@@ -0,0 +1,31 @@
+package config
+
+import "testing"
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestDiffPathsChangedOnAliasOnly(t *testing.T) {
+	old := &Config{
+		Paths: []Path{
+			{Name: "a", Alias: strPtr("old-alias")},
+		},
+	}
+
+	cur := &Config{
+		Paths: []Path{
+			{Name: "a", Alias: strPtr("new-alias")},
+		},
+	}
+
+	diff := cur.DiffPaths(old)
+
+	if len(diff.Unchanged) != 0 {
+		t.Errorf("expected no Unchanged paths, got %+v", diff.Unchanged)
+	}
+
+	if len(diff.Changed) != 1 || diff.Changed[0].Name != "a" {
+		t.Errorf("expected path %q to be Changed when only its alias differs, got %+v", "a", diff.Changed)
+	}
+}
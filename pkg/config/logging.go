@@ -0,0 +1,140 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/syslog"
+	"os"
+	"strings"
+
+	"github.com/coreos/go-systemd/v22/journal"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// NewLogger builds a *logrus.Logger configured according to c.Logging.
+// ApplyDefaults must have been called first so that Logging is never nil.
+func (c *Config) NewLogger() (*logrus.Logger, error) {
+	l := logrus.New()
+
+	formatter := dfltLogFormatter
+	sinks := []LogSink{{Type: "stderr"}}
+	if c.Logging != nil {
+		if c.Logging.Formatter != nil {
+			formatter = *c.Logging.Formatter
+		}
+		// An explicit empty list ("sinks: []") is treated the same as an
+		// omitted one: fall back to the stderr default rather than ending
+		// up with no writer at all.
+		if len(c.Logging.Sinks) > 0 {
+			sinks = c.Logging.Sinks
+		}
+	}
+
+	if formatter == "json" {
+		l.SetFormatter(&logrus.JSONFormatter{})
+	} else {
+		l.SetFormatter(&logrus.TextFormatter{})
+	}
+
+	writers := make([]io.Writer, 0, len(sinks))
+	for i := range sinks {
+		w, err := sinkWriter(sinks[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to add %q log sink", sinks[i].Type)
+		}
+
+		writers = append(writers, w)
+	}
+
+	l.SetOutput(writers[0])
+	for _, w := range writers[1:] {
+		l.AddHook(&writerHook{writer: w, formatter: l.Formatter})
+	}
+
+	return l, nil
+}
+
+func sinkWriter(s LogSink) (io.Writer, error) {
+	switch s.Type {
+	case "stderr":
+		return os.Stderr, nil
+	case "file":
+		if s.Path == nil || *s.Path == "" {
+			return nil, fmt.Errorf("file sink requires a path")
+		}
+
+		w := &lumberjack.Logger{Filename: *s.Path}
+		if s.MaxSizeMB != nil {
+			w.MaxSize = *s.MaxSizeMB
+		}
+		if s.MaxBackups != nil {
+			w.MaxBackups = *s.MaxBackups
+		}
+
+		return w, nil
+	case "syslog":
+		network, addr := "", ""
+		if s.SyslogAddr != nil {
+			network, addr = splitSyslogAddr(*s.SyslogAddr)
+		}
+
+		return syslog.Dial(network, addr, syslog.LOG_INFO, "matroschka-prober")
+	case "journald":
+		if !journal.Enabled() {
+			return nil, fmt.Errorf("journald sink requested but no journald socket is available")
+		}
+
+		return journaldWriter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown log sink type %q", s.Type)
+	}
+}
+
+// splitSyslogAddr splits a "network@address" sink address, e.g.
+// "udp@127.0.0.1:514", falling back to the local syslog daemon if network
+// is omitted.
+func splitSyslogAddr(s string) (network, addr string) {
+	parts := strings.SplitN(s, "@", 2)
+	if len(parts) != 2 {
+		return "", s
+	}
+
+	return parts[0], parts[1]
+}
+
+// writerHook lets additional log sinks, beyond the Logger's main Out, be
+// attached via logrus' hook mechanism.
+type writerHook struct {
+	writer    io.Writer
+	formatter logrus.Formatter
+}
+
+func (h *writerHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *writerHook) Fire(e *logrus.Entry) error {
+	b, err := h.formatter.Format(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.writer.Write(b)
+	return err
+}
+
+// journaldWriter adapts github.com/coreos/go-systemd/v22/journal's datagram
+// client, which sends one structured entry at a time via journal.Send, to
+// the io.Writer interface the other log sinks use.
+type journaldWriter struct{}
+
+func (journaldWriter) Write(p []byte) (int, error) {
+	if err := journal.Send(string(bytes.TrimRight(p, "\n")), journal.PriInfo, nil); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,53 @@
+package config
+
+import "testing"
+
+// TestNewLoggerExplicitEmptySinks guards against a regression where an
+// explicit empty sinks list ("logging: {sinks: []}") unmarshals into a
+// non-nil, zero-length slice, bypasses the nil-check in applyDefaults, and
+// leaves NewLogger with no writer to set as output.
+func TestNewLoggerExplicitEmptySinks(t *testing.T) {
+	c := &Config{
+		Logging: &Logging{
+			Sinks: []LogSink{},
+		},
+	}
+	c.ApplyDefaults()
+
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(c.Logging.Sinks) == 0 {
+		t.Fatal("expected ApplyDefaults to fall back to a default sink for an explicit empty list")
+	}
+
+	if _, err := c.NewLogger(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestNewLoggerDefaultsToStderr(t *testing.T) {
+	c := &Config{}
+	c.ApplyDefaults()
+
+	if _, err := c.NewLogger(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// TestValidateLoggingAcceptsJournald guards against journald being rejected
+// as an unknown sink type; actually exercising sinkWriter's journald branch
+// requires a running journald socket, which isn't available in CI.
+func TestValidateLoggingAcceptsJournald(t *testing.T) {
+	c := &Config{
+		Logging: &Logging{
+			Sinks: []LogSink{{Type: "journald"}},
+		},
+	}
+	c.ApplyDefaults()
+
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// LoadFile reads, parses, defaults and validates the config file at path.
+func LoadFile(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read config file")
+	}
+
+	c := &Config{}
+	if err := yaml.Unmarshal(b, c); err != nil {
+		return nil, errors.Wrap(err, "unable to parse config file")
+	}
+
+	c.ApplyDefaults()
+
+	if err := c.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid config")
+	}
+
+	return c, nil
+}
+
+// Reconciler reconciles running probers against a PathDiff produced by a
+// config reload. Implementations live alongside whatever owns running
+// prober goroutines, sockets and Prometheus counters (the Prober/daemon
+// code, not part of pkg/config): they are expected to start a prober for
+// each Added path, stop one for each Removed path (closing its stop channel
+// and udpConn), restart one for each Changed path, and leave Unchanged
+// paths' prober and counters untouched so scrapes don't see a reset.
+type Reconciler interface {
+	Reconcile(diff PathDiff)
+}
+
+// Watcher re-reads a config file and calls a Reconciler with the resulting
+// path diff whenever the process receives SIGHUP or the file changes on
+// disk (as reported by inotify/fsnotify).
+type Watcher struct {
+	path        string
+	current     *Config
+	reconciler  Reconciler
+	onLoadError func(error)
+	sigs        chan os.Signal
+	fsw         *fsnotify.Watcher
+	stop        chan struct{}
+}
+
+// NewWatcher creates a Watcher for the config file at path. current is the
+// already-loaded, running configuration; reconciler is called with the
+// PathDiff between current and every successfully reloaded config.
+// onLoadError, if non-nil, is called if a reload fails to parse or
+// validate; the previously running config is left untouched in that case.
+func NewWatcher(path string, current *Config, reconciler Reconciler, onLoadError func(error)) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create fsnotify watcher")
+	}
+
+	if err := fsw.Add(path); err != nil {
+		fsw.Close()
+		return nil, errors.Wrap(err, "unable to watch config file")
+	}
+
+	w := &Watcher{
+		path:        path,
+		current:     current,
+		reconciler:  reconciler,
+		onLoadError: onLoadError,
+		sigs:        make(chan os.Signal, 1),
+		fsw:         fsw,
+		stop:        make(chan struct{}),
+	}
+
+	signal.Notify(w.sigs, syscall.SIGHUP)
+
+	return w, nil
+}
+
+// Run blocks, reloading the config on SIGHUP or file change, until Stop is
+// called. It is meant to be run in its own goroutine.
+func (w *Watcher) Run() {
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-w.sigs:
+			w.reload()
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+
+			if ev.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+				// Tools that save via an atomic rename (vim, most
+				// config-management pushes) replace the inode at path,
+				// which drops it from the watch list. Re-add it so
+				// subsequent edits keep producing events. On Linux this
+				// rename-over-destination is reported as CHMOD followed by
+				// REMOVE for the watched path, never Write/Create/Rename,
+				// so Remove must also trigger a reload below.
+				if err := w.fsw.Add(w.path); err != nil {
+					log.Errorf("Unable to re-watch config file after rename/remove: %v", err)
+				}
+			}
+
+			if ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0 {
+				w.reload()
+			}
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+
+			log.Errorf("Config watcher error: %v", err)
+		}
+	}
+}
+
+// Stop terminates Run and releases the signal/fsnotify subscriptions.
+func (w *Watcher) Stop() {
+	close(w.stop)
+	signal.Stop(w.sigs)
+	w.fsw.Close()
+}
+
+func (w *Watcher) reload() {
+	c, err := LoadFile(w.path)
+	if err != nil {
+		if w.onLoadError != nil {
+			w.onLoadError(err)
+		}
+
+		return
+	}
+
+	w.reconciler.Reconcile(c.DiffPaths(w.current))
+	w.current = c
+}
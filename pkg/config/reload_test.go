@@ -0,0 +1,147 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	// Register the "udp" probe type so Validate() accepts the test fixtures.
+	_ "github.com/exaring/matroschka-prober/pkg/prober/probes/udp"
+)
+
+type fakeReconciler struct {
+	diff  PathDiff
+	calls int
+}
+
+func (f *fakeReconciler) Reconcile(diff PathDiff) {
+	f.diff = diff
+	f.calls++
+}
+
+func TestWatcherReloadDiffsPaths(t *testing.T) {
+	path := t.TempDir() + "/config.yaml"
+
+	if err := os.WriteFile(path, []byte("paths:\n  - name: a\n    hops: []\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unable to load initial config: %v", err)
+	}
+
+	rec := &fakeReconciler{}
+	w, err := NewWatcher(path, current, rec, nil)
+	if err != nil {
+		t.Fatalf("unable to create watcher: %v", err)
+	}
+	defer w.Stop()
+
+	if err := os.WriteFile(path, []byte("paths:\n  - name: b\n    hops: []\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	w.reload()
+
+	if rec.calls != 1 {
+		t.Fatalf("expected Reconcile to be called once, got %d", rec.calls)
+	}
+
+	if len(rec.diff.Added) != 1 || rec.diff.Added[0].Name != "b" {
+		t.Errorf("expected path %q to be Added, got %+v", "b", rec.diff.Added)
+	}
+
+	if len(rec.diff.Removed) != 1 || rec.diff.Removed[0].Name != "a" {
+		t.Errorf("expected path %q to be Removed, got %+v", "a", rec.diff.Removed)
+	}
+}
+
+func TestWatcherReloadReportsLoadError(t *testing.T) {
+	path := t.TempDir() + "/config.yaml"
+
+	if err := os.WriteFile(path, []byte("paths: []\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unable to load initial config: %v", err)
+	}
+
+	rec := &fakeReconciler{}
+	var gotErr error
+	w, err := NewWatcher(path, current, rec, func(e error) { gotErr = e })
+	if err != nil {
+		t.Fatalf("unable to create watcher: %v", err)
+	}
+	defer w.Stop()
+
+	if err := os.WriteFile(path, []byte("not: [valid yaml"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	w.reload()
+
+	if gotErr == nil {
+		t.Fatal("expected onLoadError to be called with a non-nil error")
+	}
+
+	if rec.calls != 0 {
+		t.Fatalf("expected Reconcile not to be called on a failed reload, got %d calls", rec.calls)
+	}
+}
+
+// TestWatcherRunReloadsOnAtomicRename drives the real Run() loop (not just
+// reload()) through an atomic rename-over-destination, the way editors and
+// config-management tools save files. On Linux this delivers CHMOD then
+// REMOVE for the watched path, never Write/Create/Rename, so Run must treat
+// Remove as a reload trigger too, not just as a cue to re-add the watch.
+func TestWatcherRunReloadsOnAtomicRename(t *testing.T) {
+	dir := t.TempDir()
+	path := dir + "/config.yaml"
+
+	if err := os.WriteFile(path, []byte("paths:\n  - name: a\n    hops: []\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unable to load initial config: %v", err)
+	}
+
+	rec := &fakeReconciler{}
+	w, err := NewWatcher(path, current, rec, nil)
+	if err != nil {
+		t.Fatalf("unable to create watcher: %v", err)
+	}
+	defer w.Stop()
+
+	go w.Run()
+
+	tmp := dir + "/config.yaml.tmp"
+	if err := os.WriteFile(tmp, []byte("paths:\n  - name: b\n    hops: []\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if rec.calls > 0 {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if rec.calls < 1 {
+		t.Fatal("expected Reconcile to be called after an atomic rename over the watched file")
+	}
+
+	if len(rec.diff.Added) != 1 || rec.diff.Added[0].Name != "b" {
+		t.Errorf("expected path %q to be Added, got %+v", "b", rec.diff.Added)
+	}
+}
@@ -0,0 +1,225 @@
+// Package histogram implements a log-linear latency histogram maintained as
+// a sliding window of time-sliced sub-buckets. It backs the per-path latency
+// metrics so that Prometheus histogram_quantile() and explicit percentile
+// gauges stay accurate without retaining individual samples.
+package histogram
+
+import (
+	"math"
+	"sync"
+)
+
+// Histogram is a log-linear latency histogram covering [min, max]
+// milliseconds at a fixed decimal precision, maintained as a ring of
+// sub-buckets so that old samples age out on Advance without a rescan.
+// Safe for concurrent use.
+type Histogram struct {
+	bounds []float64 // upper bound (ms) of each bucket, ascending
+
+	l       sync.Mutex
+	windows []window // ring of sub-buckets, one per measurement interval
+	cur     int      // index of the currently-filling window
+}
+
+type window struct {
+	counts  []uint64
+	timeout uint64
+}
+
+// New creates a Histogram covering [minMS, maxMS] with the given decimal
+// precision (1-5 significant digits, HdrHistogram-style) and sliced into
+// numWindows sub-buckets that are rotated through by Advance. numWindows
+// should match the number of MeasurementLengthMS intervals the caller wants
+// to keep in the sliding window.
+func New(minMS, maxMS float64, precision, numWindows int) *Histogram {
+	if precision < 1 {
+		precision = 1
+	}
+	if precision > 5 {
+		precision = 5
+	}
+	if numWindows < 1 {
+		numWindows = 1
+	}
+
+	h := &Histogram{
+		bounds:  bucketBounds(minMS, maxMS, precision),
+		windows: make([]window, numWindows),
+	}
+
+	for i := range h.windows {
+		h.windows[i].counts = make([]uint64, len(h.bounds))
+	}
+
+	return h
+}
+
+// bucketBounds generates log-linear bucket upper bounds: buckets double in
+// width every 10^precision values, giving a constant relative error of
+// 10^-precision across the whole range, analogous to HdrHistogram.
+func bucketBounds(minMS, maxMS float64, precision int) []float64 {
+	if minMS <= 0 {
+		minMS = 0.001
+	}
+
+	step := math.Pow(10, float64(-precision))
+	subBuckets := int(1 / step)
+
+	bounds := make([]float64, 0, 256)
+	for unit := minMS; unit < maxMS; unit *= 2 {
+		for i := 1; i <= subBuckets; i++ {
+			b := unit * (1 + float64(i)*step)
+			if b >= maxMS {
+				break
+			}
+			bounds = append(bounds, b)
+		}
+	}
+
+	return append(bounds, maxMS)
+}
+
+// Record adds a latency sample, in milliseconds, to the currently active
+// window.
+func (h *Histogram) Record(ms float64) {
+	h.l.Lock()
+	defer h.l.Unlock()
+
+	h.windows[h.cur].counts[h.bucketIndex(ms)]++
+}
+
+// RecordTimeout records a lost/timed-out probe in the currently active
+// window's dedicated timeout bucket, so lost-probe latency is representable
+// alongside successful samples.
+func (h *Histogram) RecordTimeout() {
+	h.l.Lock()
+	defer h.l.Unlock()
+
+	h.windows[h.cur].timeout++
+}
+
+func (h *Histogram) bucketIndex(ms float64) int {
+	for i, b := range h.bounds {
+		if ms <= b {
+			return i
+		}
+	}
+
+	return len(h.bounds) - 1
+}
+
+// Advance rotates the ring to the next sub-bucket, clearing the oldest one
+// so its samples age out of the sliding window. Call this once per
+// MeasurementLengthMS interval.
+func (h *Histogram) Advance() {
+	h.l.Lock()
+	defer h.l.Unlock()
+
+	h.cur = (h.cur + 1) % len(h.windows)
+	for i := range h.windows[h.cur].counts {
+		h.windows[h.cur].counts[i] = 0
+	}
+	h.windows[h.cur].timeout = 0
+}
+
+// Snapshot is a point-in-time, cumulative view of the histogram suitable for
+// rendering as Prometheus `_bucket`/`_sum`/`_count` series.
+type Snapshot struct {
+	// Bounds are the upper bounds (ms) of each bucket, ascending, matching
+	// the `le` label of a Prometheus histogram.
+	Bounds []float64
+	// CumulativeCounts holds, for each entry in Bounds, the count of
+	// samples with value <= that bound.
+	CumulativeCounts []uint64
+	// Count is the total number of non-timeout samples across the window.
+	Count uint64
+	// Sum is the approximate sum (ms) of all recorded samples, computed
+	// from bucket midpoints since individual values aren't retained.
+	Sum float64
+	// Timeouts is the number of timed-out/lost probes across the window.
+	Timeouts uint64
+}
+
+// Snapshot sums all sub-buckets in the ring into a single cumulative view.
+func (h *Histogram) Snapshot() Snapshot {
+	h.l.Lock()
+	defer h.l.Unlock()
+
+	s := Snapshot{
+		Bounds:           append([]float64(nil), h.bounds...),
+		CumulativeCounts: make([]uint64, len(h.bounds)),
+	}
+
+	counts := make([]uint64, len(h.bounds))
+	for i := range h.windows {
+		for j, c := range h.windows[i].counts {
+			counts[j] += c
+		}
+		s.Timeouts += h.windows[i].timeout
+	}
+
+	var cum uint64
+	lower := 0.0
+	for i, c := range counts {
+		cum += c
+		s.CumulativeCounts[i] = cum
+		s.Sum += (lower + h.bounds[i]) / 2 * float64(c)
+		lower = h.bounds[i]
+	}
+	s.Count = cum
+
+	return s
+}
+
+// Quantile returns the latency (ms) below which q (0-1) of samples fall,
+// linearly interpolated within the bucket containing the target rank.
+func (s Snapshot) Quantile(q float64) float64 {
+	if s.Count == 0 {
+		return 0
+	}
+
+	target := uint64(q * float64(s.Count))
+	lower, lowerCum := 0.0, uint64(0)
+	for i, cum := range s.CumulativeCounts {
+		if cum >= target {
+			if cum == lowerCum {
+				return s.Bounds[i]
+			}
+
+			frac := float64(target-lowerCum) / float64(cum-lowerCum)
+			return lower + frac*(s.Bounds[i]-lower)
+		}
+
+		lower, lowerCum = s.Bounds[i], cum
+	}
+
+	return s.Bounds[len(s.Bounds)-1]
+}
+
+// StandardPercentiles are the percentiles exposed as gauges per path
+// (p50/p95/p99/p999).
+var StandardPercentiles = []float64{0.50, 0.95, 0.99, 0.999}
+
+// PercentileGauges computes StandardPercentiles against s, keyed by the
+// percentile itself (e.g. 0.99 for p99), ready to be set on Prometheus
+// gauges.
+func (s Snapshot) PercentileGauges() map[float64]float64 {
+	gauges := make(map[float64]float64, len(StandardPercentiles))
+	for _, p := range StandardPercentiles {
+		gauges[p] = s.Quantile(p)
+	}
+
+	return gauges
+}
+
+// Buckets returns the cumulative bucket counts keyed by upper bound (ms),
+// in the shape Prometheus' constant-histogram metrics expect for rendering
+// `_bucket` series compatible with histogram_quantile().
+func (s Snapshot) Buckets() map[float64]uint64 {
+	buckets := make(map[float64]uint64, len(s.Bounds))
+	for i, b := range s.Bounds {
+		buckets[b] = s.CumulativeCounts[i]
+	}
+
+	return buckets
+}
@@ -0,0 +1,95 @@
+package histogram
+
+import "testing"
+
+func TestRecordAndSnapshotCount(t *testing.T) {
+	h := New(0.01, 1000, 3, 4)
+
+	for i := 0; i < 100; i++ {
+		h.Record(10)
+	}
+
+	s := h.Snapshot()
+	if s.Count != 100 {
+		t.Fatalf("expected count 100, got %d", s.Count)
+	}
+}
+
+func TestRecordTimeout(t *testing.T) {
+	h := New(0.01, 1000, 3, 4)
+
+	h.Record(10)
+	h.RecordTimeout()
+	h.RecordTimeout()
+
+	s := h.Snapshot()
+	if s.Count != 1 {
+		t.Fatalf("expected count 1, got %d", s.Count)
+	}
+
+	if s.Timeouts != 2 {
+		t.Fatalf("expected 2 timeouts, got %d", s.Timeouts)
+	}
+}
+
+func TestAdvanceAgesOutOldSamples(t *testing.T) {
+	h := New(0.01, 1000, 3, 2)
+
+	h.Record(10)
+	s := h.Snapshot()
+	if s.Count != 1 {
+		t.Fatalf("expected count 1 before Advance, got %d", s.Count)
+	}
+
+	h.Advance()
+	h.Advance()
+
+	s = h.Snapshot()
+	if s.Count != 0 {
+		t.Fatalf("expected count 0 after the sample's window aged out, got %d", s.Count)
+	}
+}
+
+func TestQuantileMonotonic(t *testing.T) {
+	h := New(0.01, 1000, 3, 1)
+
+	for _, v := range []float64{1, 2, 3, 4, 5, 100, 200, 300} {
+		h.Record(v)
+	}
+
+	s := h.Snapshot()
+
+	prev := 0.0
+	for _, q := range []float64{0.1, 0.5, 0.9, 0.99} {
+		v := s.Quantile(q)
+		if v < prev {
+			t.Fatalf("quantile %v = %v is lower than quantile at a smaller q (%v)", q, v, prev)
+		}
+		prev = v
+	}
+
+	p99 := s.Quantile(0.99)
+	if p99 < 100 || p99 > 300 {
+		t.Fatalf("expected p99 within the top end of the recorded samples (100-300ms), got %v", p99)
+	}
+}
+
+func TestBucketsAndPercentileGauges(t *testing.T) {
+	h := New(0.01, 1000, 3, 1)
+	h.Record(5)
+	h.Record(500)
+
+	s := h.Snapshot()
+
+	buckets := s.Buckets()
+	if len(buckets) != len(s.Bounds) {
+		t.Fatalf("expected %d buckets, got %d", len(s.Bounds), len(buckets))
+	}
+
+	gauges := s.PercentileGauges()
+	for _, p := range StandardPercentiles {
+		if _, ok := gauges[p]; !ok {
+			t.Errorf("missing gauge for percentile %v", p)
+		}
+	}
+}
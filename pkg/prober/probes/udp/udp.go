@@ -0,0 +1,62 @@
+// Package udp implements matroschka-prober's original probe type: a UDP
+// datagram encapsulated in IP-in-IP, one per configured hop, carrying a
+// sequence number and send timestamp. It registers itself under the name
+// "udp" so that it is picked up automatically by any binary that
+// blank-imports this package.
+package udp
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/exaring/matroschka-prober/pkg/prober"
+)
+
+// headerSize is the wire size, in bytes, of the sequence number and send
+// timestamp carried by every probe.
+const headerSize = 16
+
+func init() {
+	prober.Register("udp", New)
+}
+
+// Probe is the "udp" probe type.
+type Probe struct{}
+
+// New constructs a udp Probe.
+func New() prober.Probe {
+	return &Probe{}
+}
+
+// Name returns "udp".
+func (p *Probe) Name() string {
+	return "udp"
+}
+
+// Marshal encodes sequenceNumber and timestampNS as two big-endian uint64s,
+// padded with zero bytes up to payloadSize.
+func (p *Probe) Marshal(sequenceNumber uint64, timestampNS int64, payloadSize uint64) ([]byte, error) {
+	size := headerSize
+	if uint64(size) < payloadSize {
+		size = int(payloadSize)
+	}
+
+	b := make([]byte, size)
+	binary.BigEndian.PutUint64(b[0:8], sequenceNumber)
+	binary.BigEndian.PutUint64(b[8:16], uint64(timestampNS))
+
+	return b, nil
+}
+
+// Unmarshal recovers the sequence number and send timestamp encoded by
+// Marshal.
+func (p *Probe) Unmarshal(b []byte) (sequenceNumber uint64, timestampNS int64, err error) {
+	if len(b) < headerSize {
+		return 0, 0, fmt.Errorf("udp: packet too short: got %d bytes, need at least %d", len(b), headerSize)
+	}
+
+	sequenceNumber = binary.BigEndian.Uint64(b[0:8])
+	timestampNS = int64(binary.BigEndian.Uint64(b[8:16]))
+
+	return sequenceNumber, timestampNS, nil
+}
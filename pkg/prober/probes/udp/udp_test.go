@@ -0,0 +1,46 @@
+package udp
+
+import "testing"
+
+func TestMarshalUnmarshalRoundTrip(t *testing.T) {
+	p := New()
+
+	b, err := p.Marshal(42, 1234567890, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	seq, ts, err := p.Unmarshal(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if seq != 42 {
+		t.Errorf("expected sequence number 42, got %d", seq)
+	}
+
+	if ts != 1234567890 {
+		t.Errorf("expected timestamp 1234567890, got %d", ts)
+	}
+}
+
+func TestMarshalPadsToPayloadSize(t *testing.T) {
+	p := New()
+
+	b, err := p.Marshal(1, 1, 64)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(b) != 64 {
+		t.Fatalf("expected 64 byte packet, got %d", len(b))
+	}
+}
+
+func TestUnmarshalRejectsShortPacket(t *testing.T) {
+	p := New()
+
+	if _, _, err := p.Unmarshal([]byte{1, 2, 3}); err == nil {
+		t.Fatal("expected an error for a too-short packet, got nil")
+	}
+}
@@ -2,14 +2,17 @@ package prober
 
 import (
 	"sync/atomic"
-	"time"
-
-	log "github.com/sirupsen/logrus"
 )
 
 func (p *Prober) receiver() {
 	defer p.udpConn.Close()
 
+	mode := parseTimestampMode(p.cfg.Timestamping)
+	if err := enableKernelTimestamping(p.udpConn, mode); err != nil {
+		p.log.Errorf("Unable to enable %q receive timestamping, falling back to userspace: %v", mode, err)
+		mode = timestampUserspace
+	}
+
 	recvBuffer := make([]byte, p.mtu)
 	for {
 		select {
@@ -18,35 +21,45 @@ func (p *Prober) receiver() {
 		default:
 		}
 
-		_, err := p.udpConn.Read(recvBuffer)
-		now := time.Now().UnixNano()
+		var (
+			now int64
+			err error
+		)
+		if mode == timestampUserspace {
+			_, err = p.udpConn.Read(recvBuffer)
+			now = userspaceNow()
+		} else {
+			_, now, err = recvWithKernelTimestamp(p.udpConn, recvBuffer)
+		}
 		if err != nil {
-			log.Errorf("Unable to read from UDP socket: %v", err)
+			p.log.Errorf("Unable to read from UDP socket: %v", err)
 			return
 		}
 
 		atomic.AddUint64(&p.probesReceived, 1)
 
-		pkt, err := unmarshal(recvBuffer)
+		sequenceNumber, timestampNS, err := p.probe.Unmarshal(recvBuffer)
 		if err != nil {
-			log.Errorf("Unable to unmarshal message: %v", err)
+			p.log.Errorf("Unable to unmarshal message: %v", err)
 			return
 		}
 
-		err = p.transitProbes.remove(pkt.SequenceNumber)
+		err = p.transitProbes.remove(sequenceNumber)
 		if err != nil {
 			// Probe was count as lost, so we ignore it from here on
 			continue
 		}
 
-		rtt := now - pkt.TimeStamp
+		rtt := now - timestampNS
 		if p.timedOut(rtt) {
 			// Probe arrived late. rttTimoutChecker() will clean up after it. So we ignore it from here on
 			atomic.AddUint64(&p.latePackets, 1)
+			p.histogram.RecordTimeout()
 			continue
 		}
 
-		p.measurements.AddRecv(pkt.TimeStamp, uint64(rtt), p.cfg.MeasurementLengthMS)
+		p.measurements.AddRecv(timestampNS, uint64(rtt), p.cfg.MeasurementLengthMS)
+		p.histogram.Record(float64(rtt) / 1e6)
 	}
 }
 
@@ -0,0 +1,62 @@
+package prober
+
+import "fmt"
+
+// Probe is implemented by every probe type that can be referenced from a
+// Path's `type` setting. Concrete implementations live in their own
+// subpackage under pkg/prober/probes and register themselves with Register
+// from an init() function. A Probe owns the wire format of a single probe
+// type: how a sequence number and send timestamp are encoded into an
+// outgoing packet, and how they're recovered from the reply.
+type Probe interface {
+	// Name returns the name the probe type is registered under.
+	Name() string
+
+	// Marshal encodes a probe with the given sequence number and send
+	// timestamp (nanoseconds since the Unix epoch) into a wire-format
+	// payload, padded to payloadSize bytes.
+	Marshal(sequenceNumber uint64, timestampNS int64, payloadSize uint64) ([]byte, error)
+
+	// Unmarshal decodes a wire-format payload received back from a hop,
+	// returning the sequence number and send timestamp it carries.
+	Unmarshal(b []byte) (sequenceNumber uint64, timestampNS int64, err error)
+}
+
+// Factory constructs a new Probe instance for a path configured with this
+// probe type.
+type Factory func() Probe
+
+var registry = map[string]Factory{}
+
+// Register makes a probe type available under name. It is meant to be
+// called from the init() function of a pkg/prober/probes/* subpackage, e.g.:
+//
+//	func init() {
+//		prober.Register("udp", New)
+//	}
+//
+// Register panics if name is already registered.
+func Register(name string, f Factory) {
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("prober: probe type %q registered twice", name))
+	}
+
+	registry[name] = f
+}
+
+// Lookup returns the factory registered under name, if any.
+func Lookup(name string) (Factory, bool) {
+	f, ok := registry[name]
+	return f, ok
+}
+
+// Registered returns the names of all currently registered probe types, used
+// to validate configuration and to print usage information.
+func Registered() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+
+	return names
+}
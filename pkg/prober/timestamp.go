@@ -0,0 +1,40 @@
+package prober
+
+import "time"
+
+// timestampMode selects how the receive timestamp for an inbound probe is
+// obtained. Kernel and hardware timestamps are sourced via SO_TIMESTAMPNS /
+// SO_TIMESTAMPING socket options rather than an eBPF program: they reach the
+// same kernel-timestamped-RTT goal without needing a BPF loader, a kernel
+// version new enough for CO-RE, or root/CAP_BPF at runtime.
+type timestampMode string
+
+const (
+	// timestampUserspace records the receive time right after the read
+	// syscall returns. Works on every platform, but folds scheduling
+	// latency and syscall wakeup delay into the measured RTT.
+	timestampUserspace timestampMode = "userspace"
+	// timestampKernel asks the kernel for a software receive timestamp
+	// (SO_TIMESTAMPNS) taken when the packet was delivered to the socket,
+	// removing userspace scheduling jitter from the RTT. Linux only.
+	timestampKernel timestampMode = "kernel"
+	// timestampHardware asks the NIC for a hardware receive timestamp
+	// (SO_TIMESTAMPING) where supported, removing kernel scheduling jitter
+	// as well. Linux only, requires NIC driver support.
+	timestampHardware timestampMode = "hardware"
+)
+
+func parseTimestampMode(s string) timestampMode {
+	switch timestampMode(s) {
+	case timestampKernel:
+		return timestampKernel
+	case timestampHardware:
+		return timestampHardware
+	default:
+		return timestampUserspace
+	}
+}
+
+func userspaceNow() int64 {
+	return time.Now().UnixNano()
+}
@@ -0,0 +1,124 @@
+//go:build linux
+
+package prober
+
+import (
+	"fmt"
+	"net"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// enableKernelTimestamping turns on SO_TIMESTAMPNS (mode == timestampKernel)
+// or SO_TIMESTAMPING (mode == timestampHardware) on conn's underlying socket.
+// It is a no-op for timestampUserspace.
+func enableKernelTimestamping(conn *net.UDPConn, mode timestampMode) error {
+	if mode == timestampUserspace {
+		return nil
+	}
+
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("unable to get raw connection: %v", err)
+	}
+
+	var sockErr error
+	err = raw.Control(func(fd uintptr) {
+		switch mode {
+		case timestampKernel:
+			sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_TIMESTAMPNS, 1)
+		case timestampHardware:
+			flags := unix.SOF_TIMESTAMPING_RX_HARDWARE |
+				unix.SOF_TIMESTAMPING_RAW_HARDWARE |
+				unix.SOF_TIMESTAMPING_SOFTWARE
+			sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_TIMESTAMPING, flags)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("unable to set socket options: %v", err)
+	}
+
+	return sockErr
+}
+
+// recvWithKernelTimestamp reads a single datagram from conn into buf and
+// returns its length along with the kernel/hardware receive timestamp found
+// in the ancillary data of the underlying recvmsg(2) call. It falls back to
+// the current userspace time if the kernel did not attach one.
+func recvWithKernelTimestamp(conn *net.UDPConn, buf []byte) (int, int64, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to get raw connection: %v", err)
+	}
+
+	oob := make([]byte, unix.CmsgSpace(int(unsafe.Sizeof(unix.Timespec{}))*3))
+
+	var n, oobn int
+	var recvErr error
+	err = raw.Read(func(fd uintptr) bool {
+		n, oobn, _, _, recvErr = unix.Recvmsg(int(fd), buf, oob, 0)
+		return recvErr != unix.EAGAIN
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	if recvErr != nil {
+		return 0, 0, recvErr
+	}
+
+	if ts, ok := parseTimestampCmsg(oob[:oobn]); ok {
+		return n, ts, nil
+	}
+
+	return n, userspaceNow(), nil
+}
+
+// parseTimestampCmsg extracts a receive timestamp from SCM_TIMESTAMPNS or
+// SCM_TIMESTAMPING ancillary data.
+func parseTimestampCmsg(oob []byte) (int64, bool) {
+	cmsgs, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return 0, false
+	}
+
+	for _, c := range cmsgs {
+		if c.Header.Level != unix.SOL_SOCKET {
+			continue
+		}
+
+		switch c.Header.Type {
+		case unix.SO_TIMESTAMPNS:
+			if ts, ok := decodeTimespec(c.Data, 0); ok {
+				return ts, true
+			}
+		case unix.SO_TIMESTAMPING:
+			// SCM_TIMESTAMPING carries three timespecs: software, a
+			// deprecated legacy field, and hardware. Prefer hardware.
+			if ts, ok := decodeTimespec(c.Data, 2); ok && ts != 0 {
+				return ts, true
+			}
+			if ts, ok := decodeTimespec(c.Data, 0); ok {
+				return ts, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+func decodeTimespec(data []byte, idx int) (int64, bool) {
+	size := int(unsafe.Sizeof(unix.Timespec{}))
+	off := idx * size
+	if len(data) < off+size {
+		return 0, false
+	}
+
+	ts := *(*unix.Timespec)(unsafe.Pointer(&data[off]))
+	if ts.Sec == 0 && ts.Nsec == 0 {
+		return 0, false
+	}
+
+	return ts.Sec*int64(time.Second) + int64(ts.Nsec), true
+}
@@ -0,0 +1,25 @@
+//go:build !linux
+
+package prober
+
+import (
+	"fmt"
+	"net"
+)
+
+// enableKernelTimestamping is only implemented on Linux. On other platforms
+// requesting anything but timestampUserspace is an error.
+func enableKernelTimestamping(conn *net.UDPConn, mode timestampMode) error {
+	if mode == timestampUserspace {
+		return nil
+	}
+
+	return fmt.Errorf("timestamping mode %q is only supported on Linux", mode)
+}
+
+// recvWithKernelTimestamp is unused outside Linux; callers must not select
+// timestampKernel or timestampHardware on this platform.
+func recvWithKernelTimestamp(conn *net.UDPConn, buf []byte) (int, int64, error) {
+	n, err := conn.Read(buf)
+	return n, userspaceNow(), err
+}